@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+)
+
+// BulkImportLine is a single line of the newline-delimited JSON stream
+// accepted by BulkImport.
+type BulkImportLine struct {
+	Cookie     string `json:"cookie"`
+	Category   string `json:"category"`
+	TTLSeconds int64  `json:"ttl_seconds"`
+}
+
+// BulkImportResult summarizes a bulk import run.
+type BulkImportResult struct {
+	Accepted int      `json:"accepted"`
+	Rejected int      `json:"rejected"`
+	Errors   []string `json:"errors"`
+}
+
+// BulkImport accepts a newline-delimited JSON stream of cookies and saves
+// them in configurably-sized Redis pipeline batches, so large imports don't
+// require holding every record in memory or paying one round trip per
+// cookie like repeated calls to SaveCookie would.
+func BulkImport(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	vars := mux.Vars(r)
+	userID := vars["user_id"]
+	cookieType := vars["cookie_type"]
+	categoryFilter := r.URL.Query().Get("category")
+
+	if !authorizeCookieType(r, cookieType) {
+		errorAndRecord(w, "Forbidden", http.StatusForbidden, "bulkImport", cookieType, start)
+		return
+	}
+
+	ctx := context.Background()
+	rdb := GetRedisClient()
+	config := GetConfig()
+
+	result := BulkImportResult{Errors: []string{}}
+	batch := make([]BulkImportLine, 0, config.BulkImportBatchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := importBatch(ctx, rdb, config, userID, cookieType, batch); err != nil {
+			result.Rejected += len(batch)
+			result.Errors = append(result.Errors, err.Error())
+		} else {
+			result.Accepted += len(batch)
+		}
+		batch = batch[:0]
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry BulkImportLine
+		if err := json.Unmarshal(line, &entry); err != nil || entry.Cookie == "" {
+			result.Rejected++
+			result.Errors = append(result.Errors, "invalid line: "+string(line))
+			continue
+		}
+		if entry.Category == "" {
+			entry.Category = "default"
+		}
+		if categoryFilter != "" && entry.Category != categoryFilter {
+			result.Rejected++
+			result.Errors = append(result.Errors, "category does not match filter: "+entry.Category)
+			continue
+		}
+
+		batch = append(batch, entry)
+		if len(batch) >= config.BulkImportBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		errorAndRecord(w, "Failed to read import stream", http.StatusBadRequest, "bulkImport", cookieType, start)
+		return
+	}
+
+	recordOperation("bulkImport", cookieType, "success", start)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// importBatch encrypts and writes a batch of cookies in a single Redis
+// pipeline flush, mirroring the per-record writes SaveCookie performs but
+// without a transaction - a partial batch failure just reduces the
+// reported accepted count rather than rolling back the whole import.
+func importBatch(ctx context.Context, rdb redis.UniversalClient, config *Config, userID, cookieType string, batch []BulkImportLine) error {
+	timestamp := time.Now().Unix()
+	categoriesKey := generateCategoriesKey(userID, cookieType)
+	cookieIndexKey := generateCookieIndexKey(userID, cookieType)
+	statsKey := generateStatsKey(userID, cookieType)
+	expiryKey := generateExpiryKey(userID, cookieType)
+
+	// Categories whose previously-indexed record got superseded by a
+	// different category in this batch, and so need their stats/
+	// categories-set entries checked for a zero count once the batch lands.
+	var movedFromCategories []string
+
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, entry := range batch {
+			cookieData, recordID, _, err := newCookieRecord(config, entry.Cookie, entry.Category, timestamp)
+			if err != nil {
+				return err
+			}
+			cookieJSON, err := json.Marshal(cookieData)
+			if err != nil {
+				return err
+			}
+
+			cookieKey := generateCookieKey(userID, cookieType, entry.Category)
+			idx := cookieIndexHMAC(config, entry.Cookie)
+
+			// Re-importing a cookie that's already stored (a resumed
+			// import, a restore from BulkExport's own output) mints a
+			// fresh record id, so the previous physical record has to be
+			// superseded the same way SaveCookie does or it becomes a
+			// permanently orphaned duplicate.
+			prevEntry, err := rdb.HGet(ctx, cookieIndexKey, idx).Result()
+			if err == nil {
+				previousCategory, previousRecordID, ok := parseIndexEntry(prevEntry)
+				if !ok {
+					return fmt.Errorf("malformed cookie index entry %q", prevEntry)
+				}
+				pipe.HDel(ctx, generateCookieKey(userID, cookieType, previousCategory), previousRecordID)
+				pipe.HIncrBy(ctx, statsKey, previousCategory, -1)
+				pipe.ZRem(ctx, expiryKey, expiryMember(previousCategory, previousRecordID, idx))
+				if previousCategory != entry.Category {
+					movedFromCategories = append(movedFromCategories, previousCategory)
+				}
+			} else if err != redis.Nil {
+				return err
+			}
+
+			pipe.HSet(ctx, cookieKey, recordID, string(cookieJSON))
+			pipe.SAdd(ctx, categoriesKey, entry.Category)
+			pipe.HSet(ctx, cookieIndexKey, idx, entry.Category+":"+recordID)
+			pipe.HIncrBy(ctx, statsKey, entry.Category, 1)
+
+			ttlSeconds := entry.TTLSeconds
+			if ttlSeconds == 0 {
+				ttlSeconds = int64(config.DefaultCookieTTL)
+			}
+			if ttlSeconds > 0 {
+				expireAt := timestamp + ttlSeconds
+				member := expiryMember(entry.Category, recordID, idx)
+				pipe.ZAdd(ctx, expiryKey, &redis.Z{Score: float64(expireAt), Member: member})
+				pipe.SAdd(ctx, knownBucketsKey, bucketID(userID, cookieType))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// For any record that moved categories, clean up the old category's
+	// stats/categories-set entries the same way SaveCookie does once the
+	// count drops to zero.
+	for _, category := range movedFromCategories {
+		if count, err := rdb.HGet(ctx, statsKey, category).Int(); err == nil && count <= 0 {
+			rdb.HDel(ctx, statsKey, category)
+			cookiesPerCategory.DeleteLabelValues(userID, cookieType, category)
+		}
+		previousCookieKey := generateCookieKey(userID, cookieType, category)
+		if remaining, err := rdb.HLen(ctx, previousCookieKey).Result(); err == nil && remaining == 0 {
+			rdb.SRem(ctx, categoriesKey, category)
+		}
+	}
+
+	return nil
+}
+
+// BulkExport streams every stored cookie for a user/cookie_type back as
+// newline-delimited JSON, so operators can migrate or back up an account
+// without holding the whole dataset in memory the way GetCookies does.
+func BulkExport(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	vars := mux.Vars(r)
+	userID := vars["user_id"]
+	cookieType := vars["cookie_type"]
+	categoryFilter := r.URL.Query().Get("category")
+
+	if !authorizeCookieType(r, cookieType) {
+		errorAndRecord(w, "Forbidden", http.StatusForbidden, "bulkExport", cookieType, start)
+		return
+	}
+
+	ctx := context.Background()
+	rdb := GetRedisClient()
+	config := GetConfig()
+
+	var categories []string
+	if categoryFilter != "" {
+		categories = []string{categoryFilter}
+	} else {
+		var err error
+		categories, err = rdb.SMembers(ctx, generateCategoriesKey(userID, cookieType)).Result()
+		if err != nil {
+			errorAndRecord(w, "Failed to export cookies", http.StatusInternalServerError, "bulkExport", cookieType, start)
+			return
+		}
+	}
+
+	// Expired records are filtered out here the same way GetCookies does,
+	// since Redis has no native per-field hash TTL to rely on - otherwise
+	// an "export everything" backup would include cookies the rest of the
+	// API already considers gone.
+	expiryKey := generateExpiryKey(userID, cookieType)
+	now := time.Now().Unix()
+	expiredMembers, err := rdb.ZRangeByScore(ctx, expiryKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now, 10),
+	}).Result()
+	if err != nil {
+		errorAndRecord(w, "Failed to export cookies", http.StatusInternalServerError, "bulkExport", cookieType, start)
+		return
+	}
+	expired := make(map[string]bool, len(expiredMembers))
+	for _, member := range expiredMembers {
+		if cat, recordID, _, ok := parseExpiryMember(member); ok {
+			expired[cat+":"+recordID] = true
+		}
+	}
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Transfer-Encoding", "chunked")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	for _, cat := range categories {
+		cookieKey := generateCookieKey(userID, cookieType, cat)
+		var cursor uint64
+		for {
+			keys, nextCursor, err := rdb.HScan(ctx, cookieKey, cursor, "", 100).Result()
+			if err != nil {
+				break
+			}
+			for i := 0; i+1 < len(keys); i += 2 {
+				recordID := keys[i]
+				if expired[cat+":"+recordID] {
+					continue
+				}
+				var cookieData CookieData
+				if json.Unmarshal([]byte(keys[i+1]), &cookieData) != nil {
+					continue
+				}
+				secret, err := unwrapSecret(config, cookieData.SecretID)
+				if err != nil {
+					continue
+				}
+				plaintext, err := decryptCookie(secret, cookieData.Nonce, cookieData.Ciphertext)
+				if err != nil {
+					continue
+				}
+				encoder.Encode(PlainCookie{
+					Cookie:    plaintext,
+					Category:  cookieData.Category,
+					Timestamp: cookieData.Timestamp,
+				})
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			cursor = nextCursor
+			if cursor == 0 {
+				break
+			}
+		}
+	}
+
+	for _, member := range expiredMembers {
+		sweepExpiredRecord(ctx, rdb, userID, cookieType, member)
+	}
+
+	recordOperation("bulkExport", cookieType, "success", start)
+}
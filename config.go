@@ -2,25 +2,79 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/joho/godotenv"
 )
 
+// defaultKeyRPS and defaultKeyBurst are applied to any API key in the keys
+// file that doesn't specify its own quota.
+const (
+	defaultKeyRPS   = 10
+	defaultKeyBurst = 5
+)
+
+// APIKeyConfig holds the quota and namespace ACL for a single API key, one
+// entry of the keys file that replaced the single shared API_KEY so
+// different partners can carry distinct rate limits and cookie_type access.
+type APIKeyConfig struct {
+	Name  string `json:"name"`
+	RPS   int    `json:"rps"`
+	Burst int    `json:"burst"`
+	// AllowedCookieTypes restricts this key to the listed cookie_type
+	// namespaces. An empty list means no restriction.
+	AllowedCookieTypes []string `json:"allowed_cookie_types"`
+}
+
 // Configuration structure
 type Config struct {
-	APIKey        string
+	APIKeys       map[string]APIKeyConfig
+	EncryptionKey string
+	RedisURL      string
+	RedisMode     string
 	RedisAddr     string
 	RedisPassword string
 	RedisDB       int
-	Port          string
+
+	RedisSentinelAddrs  []string
+	RedisSentinelMaster string
+	RedisClusterAddrs   []string
+
+	// DefaultCookieTTL is applied to any saved cookie that doesn't specify
+	// its own ttl_seconds. Zero means cookies never expire by default.
+	DefaultCookieTTL int
+	// SweepIntervalSeconds controls how often the background goroutine
+	// started from main sweeps expired cookies.
+	SweepIntervalSeconds int
+
+	// LogLevel controls the structured logger's verbosity (e.g. "debug",
+	// "info", "warn", "error").
+	LogLevel string
+
+	// BulkImportBatchSize controls how many NDJSON lines the bulk import
+	// endpoint batches into a single Redis pipeline flush.
+	BulkImportBatchSize int
+
+	Port string
 }
 
-// Global Redis client
-var rdb *redis.Client
+// Global Redis client. redis.UniversalClient is satisfied by *redis.Client,
+// *redis.ClusterClient and *redis.SentinelClient alike, so SaveCookie,
+// RemoveCookie, GetCookies and GetStats work unchanged regardless of
+// RedisMode.
+var rdb redis.UniversalClient
+
+// appConfig is the process-wide Config loaded once by main's call to
+// LoadConfig. Request-path code reads it through GetConfig instead of
+// calling LoadConfig itself, which would otherwise re-read keys.json and
+// re-parse the environment on every single request.
+var appConfig *Config
 
 // Load configuration from environment variables
 func LoadConfig() *Config {
@@ -31,21 +85,75 @@ func LoadConfig() *Config {
 	}
 
 	config := &Config{
-		APIKey:        getEnv("API_KEY", ""), // No default - must be set
+		APIKeys:       loadAPIKeys(),
+		EncryptionKey: getEnv("X_ENCRYPTION_KEY", ""), // No default - must be set
+		RedisURL:      getEnv("REDIS_URL", ""),
+		RedisMode:     getEnv("REDIS_MODE", "standalone"),
 		RedisAddr:     getEnv("REDIS_ADDR", "127.0.0.1:6379"),
 		RedisPassword: getEnv("REDIS_PASSWORD", ""),
 		RedisDB:       0,
-		Port:          getEnv("PORT", "8080"),
+
+		RedisSentinelAddrs:  splitEnvList("REDIS_SENTINEL_ADDRS"),
+		RedisSentinelMaster: getEnv("REDIS_SENTINEL_MASTER", ""),
+		RedisClusterAddrs:   splitEnvList("REDIS_CLUSTER_ADDRS"),
+
+		DefaultCookieTTL:     getEnvInt("DEFAULT_COOKIE_TTL", 0),
+		SweepIntervalSeconds: getEnvInt("EXPIRY_SWEEP_INTERVAL_SECONDS", 60),
+
+		LogLevel: getEnv("LOG_LEVEL", "info"),
+
+		BulkImportBatchSize: getEnvInt("BULK_IMPORT_BATCH_SIZE", 100),
+
+		Port: getEnv("PORT", "8080"),
 	}
 
 	// Validate required configuration
-	if config.APIKey == "" {
-		log.Fatal("API_KEY environment variable is required")
+	if len(config.APIKeys) == 0 {
+		log.Fatal("No API keys configured")
+	}
+	if config.EncryptionKey == "" {
+		log.Fatal("X_ENCRYPTION_KEY environment variable is required")
 	}
 
+	appConfig = config
 	return config
 }
 
+// GetConfig returns the Config loaded at startup by main's call to
+// LoadConfig. Handlers and middleware use this on the request path instead
+// of calling LoadConfig themselves.
+func GetConfig() *Config {
+	return appConfig
+}
+
+// loadAPIKeys reads the API_KEYS_FILE (default keys.json), a JSON object
+// mapping each API key string to its name, quota, and cookie_type ACL, so
+// every partner can carry distinct rate limits instead of sharing one
+// global API_KEY.
+func loadAPIKeys() map[string]APIKeyConfig {
+	path := getEnv("API_KEYS_FILE", "keys.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("Failed to read API keys file %s: %v", path, err)
+	}
+
+	var keys map[string]APIKeyConfig
+	if err := json.Unmarshal(data, &keys); err != nil {
+		log.Fatalf("Invalid API keys file %s: %v", path, err)
+	}
+
+	for key, keyConfig := range keys {
+		if keyConfig.RPS <= 0 {
+			keyConfig.RPS = defaultKeyRPS
+		}
+		if keyConfig.Burst <= 0 {
+			keyConfig.Burst = defaultKeyBurst
+		}
+		keys[key] = keyConfig
+	}
+	return keys
+}
+
 // Helper function to get environment variable with default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -54,36 +162,109 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-// Initialize Redis connection with high-performance settings
+// getEnvInt reads an integer environment variable with a default value.
+func getEnvInt(key string, defaultValue int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Printf("Invalid value for %s, using default %d", key, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// splitEnvList reads a comma-separated environment variable into a slice,
+// returning nil if it is unset or empty.
+func splitEnvList(key string) []string {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return nil
+	}
+	parts := strings.Split(raw, ",")
+	addrs := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			addrs = append(addrs, p)
+		}
+	}
+	return addrs
+}
+
+// Initialize Redis connection with high-performance settings, against a
+// standalone node, a Sentinel-monitored master, or a sharded Cluster
+// depending on REDIS_MODE (or a full REDIS_URL when provided).
 func InitRedis(config *Config) {
-	log.Printf("Connecting to Redis at %s...", config.RedisAddr)
-
-	rdb = redis.NewClient(&redis.Options{
-		Addr:         config.RedisAddr,
-		Password:     config.RedisPassword,
-		DB:           config.RedisDB,
-		PoolSize:     200,               // Increased for higher concurrency
-		MinIdleConns: 20,                // More idle connections
-		MaxRetries:   3,                 // Retry failed operations
-		DialTimeout:  10 * time.Second,  // Increased dial timeout
-		ReadTimeout:  5 * time.Second,   // Increased read timeout
-		WriteTimeout: 5 * time.Second,   // Increased write timeout
-		IdleTimeout:  300 * time.Second, // 5 minute idle timeout
-	})
+	switch {
+	case config.RedisURL != "":
+		log.Printf("Connecting to Redis using REDIS_URL...")
+		opts, err := redis.ParseURL(config.RedisURL)
+		if err != nil {
+			log.Fatalf("Invalid REDIS_URL: %v", err)
+		}
+		rdb = redis.NewClient(opts)
+
+	case config.RedisMode == "sentinel":
+		log.Printf("Connecting to Redis Sentinel master %q via %v...", config.RedisSentinelMaster, config.RedisSentinelAddrs)
+		rdb = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    config.RedisSentinelMaster,
+			SentinelAddrs: config.RedisSentinelAddrs,
+			Password:      config.RedisPassword,
+			DB:            config.RedisDB,
+			PoolSize:      200,
+			MinIdleConns:  20,
+			MaxRetries:    3,
+			DialTimeout:   10 * time.Second,
+			ReadTimeout:   5 * time.Second,
+			WriteTimeout:  5 * time.Second,
+			IdleTimeout:   300 * time.Second,
+		})
+
+	case config.RedisMode == "cluster":
+		log.Printf("Connecting to Redis Cluster via %v...", config.RedisClusterAddrs)
+		rdb = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        config.RedisClusterAddrs,
+			Password:     config.RedisPassword,
+			PoolSize:     200,
+			MinIdleConns: 20,
+			MaxRetries:   3,
+			DialTimeout:  10 * time.Second,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 5 * time.Second,
+			IdleTimeout:  300 * time.Second,
+		})
+
+	default:
+		log.Printf("Connecting to Redis at %s...", config.RedisAddr)
+		rdb = redis.NewClient(&redis.Options{
+			Addr:         config.RedisAddr,
+			Password:     config.RedisPassword,
+			DB:           config.RedisDB,
+			PoolSize:     200,               // Increased for higher concurrency
+			MinIdleConns: 20,                // More idle connections
+			MaxRetries:   3,                 // Retry failed operations
+			DialTimeout:  10 * time.Second,  // Increased dial timeout
+			ReadTimeout:  5 * time.Second,   // Increased read timeout
+			WriteTimeout: 5 * time.Second,   // Increased write timeout
+			IdleTimeout:  300 * time.Second, // 5 minute idle timeout
+		})
+	}
 
 	// Test connection with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	log.Printf("Attempting to connect to Redis at %s...", config.RedisAddr)
+	log.Printf("Attempting to ping Redis...")
 	pong, err := rdb.Ping(ctx).Result()
 	if err != nil {
-		log.Fatalf("Failed to connect to Redis at %s: %v", config.RedisAddr, err)
+		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
 	log.Printf("Connected to Redis successfully: %s", pong)
 }
 
 // Get Redis client instance
-func GetRedisClient() *redis.Client {
+func GetRedisClient() redis.UniversalClient {
 	return rdb
 }
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const secretSize = 32
+
+// generateSecret returns a fresh random 32-byte per-record encryption secret.
+func generateSecret() ([]byte, error) {
+	secret := make([]byte, secretSize)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return secret, nil
+}
+
+// generateRecordID returns a random identifier used as the Redis hash field
+// for an encrypted cookie record, since the plaintext cookie can no longer
+// be used as the field name.
+func generateRecordID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return "", fmt.Errorf("failed to generate record id: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// bootstrapKey derives a 32-byte AES key from the configured X-Encryption-Key
+// bootstrap key, which is used only to wrap/unwrap per-record secrets.
+func bootstrapKey(config *Config) [32]byte {
+	return sha256.Sum256([]byte(config.EncryptionKey))
+}
+
+// aesGCMEncrypt encrypts plaintext with key, returning base64-encoded nonce
+// and ciphertext suitable for JSON storage.
+func aesGCMEncrypt(key, plaintext []byte) (nonceB64, ciphertextB64 string, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", "", err
+	}
+	ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(nonce), base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// aesGCMDecrypt reverses aesGCMEncrypt.
+func aesGCMDecrypt(key []byte, nonceB64, ciphertextB64 string) ([]byte, error) {
+	nonce, err := base64.StdEncoding.DecodeString(nonceB64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(ciphertextB64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// encryptCookie encrypts a cookie value with its per-record secret.
+func encryptCookie(secret []byte, cookie string) (nonceB64, ciphertextB64 string, err error) {
+	return aesGCMEncrypt(secret, []byte(cookie))
+}
+
+// decryptCookie decrypts a cookie value using its per-record secret.
+func decryptCookie(secret []byte, nonceB64, ciphertextB64 string) (string, error) {
+	plaintext, err := aesGCMDecrypt(secret, nonceB64, ciphertextB64)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// wrapSecret seals a per-record secret under the bootstrap key so that Redis
+// alone never holds a usable encryption key.
+func wrapSecret(config *Config, secret []byte) (string, error) {
+	key := bootstrapKey(config)
+	nonceB64, ciphertextB64, err := aesGCMEncrypt(key[:], secret)
+	if err != nil {
+		return "", err
+	}
+	return nonceB64 + ":" + ciphertextB64, nil
+}
+
+// unwrapSecret reverses wrapSecret.
+func unwrapSecret(config *Config, secretID string) ([]byte, error) {
+	parts := strings.SplitN(secretID, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("malformed secret_id")
+	}
+	key := bootstrapKey(config)
+	return aesGCMDecrypt(key[:], parts[0], parts[1])
+}
+
+// cookieIndexHMAC derives a deterministic, non-reversible lookup key for a
+// plaintext cookie from the bootstrap key, so RemoveCookie can find a
+// record's category/record id in O(1) without decrypting every candidate.
+func cookieIndexHMAC(config *Config, cookie string) string {
+	key := bootstrapKey(config)
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte(cookie))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newCookieRecord encrypts a plaintext cookie under a fresh per-record
+// secret and returns the record ready for storage, the generated record id,
+// and the raw secret (so SaveCookie can mint a ticket without unwrapping
+// SecretID right back), shared by SaveCookie and the bulk import endpoint.
+func newCookieRecord(config *Config, cookie, category string, timestamp int64) (CookieData, string, []byte, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return CookieData{}, "", nil, err
+	}
+	nonce, ciphertext, err := encryptCookie(secret, cookie)
+	if err != nil {
+		return CookieData{}, "", nil, err
+	}
+	secretID, err := wrapSecret(config, secret)
+	if err != nil {
+		return CookieData{}, "", nil, err
+	}
+	recordID, err := generateRecordID()
+	if err != nil {
+		return CookieData{}, "", nil, err
+	}
+
+	return CookieData{
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+		SecretID:   secretID,
+		Category:   category,
+		Timestamp:  timestamp,
+	}, recordID, secret, nil
+}
+
+// buildTicket packs a per-record secret and its record key into the opaque
+// handle callers must present to retrieve a cookie's plaintext.
+func buildTicket(secret []byte, category, recordID string) string {
+	recordKey := category + ":" + recordID
+	raw := append(append([]byte{}, secret...), []byte(recordKey)...)
+	return base64.URLEncoding.EncodeToString(raw)
+}
+
+// parseTicket reverses buildTicket. It splits the packed record key
+// right-anchored on the last colon, the same way parseIndexEntry does,
+// since category is client-supplied and may itself contain colons; record
+// ids are always hex and therefore colon-free, so the last ":" unambiguously
+// separates the two.
+func parseTicket(ticket string) (secret []byte, category, recordID string, err error) {
+	raw, err := base64.URLEncoding.DecodeString(ticket)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("invalid ticket")
+	}
+	if len(raw) <= secretSize {
+		return nil, "", "", fmt.Errorf("invalid ticket")
+	}
+	secret = raw[:secretSize]
+	recordKey := string(raw[secretSize:])
+	sep := strings.LastIndex(recordKey, ":")
+	if sep < 0 {
+		return nil, "", "", fmt.Errorf("invalid ticket")
+	}
+	return secret, recordKey[:sep], recordKey[sep+1:], nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import "testing"
+
+func TestBuildAndParseTicket(t *testing.T) {
+	cases := []struct {
+		name     string
+		category string
+		recordID string
+	}{
+		{"simple category", "default", "abc123"},
+		{"category with colon", "session:mobile", "deadbeef"},
+		{"category with multiple colons", "a:b:c", "cafef00d"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			secret, err := generateSecret()
+			if err != nil {
+				t.Fatalf("generateSecret() error = %v", err)
+			}
+
+			ticket := buildTicket(secret, tc.category, tc.recordID)
+
+			gotSecret, gotCategory, gotRecordID, err := parseTicket(ticket)
+			if err != nil {
+				t.Fatalf("parseTicket() error = %v", err)
+			}
+			if string(gotSecret) != string(secret) {
+				t.Errorf("secret = %x, want %x", gotSecret, secret)
+			}
+			if gotCategory != tc.category {
+				t.Errorf("category = %q, want %q", gotCategory, tc.category)
+			}
+			if gotRecordID != tc.recordID {
+				t.Errorf("recordID = %q, want %q", gotRecordID, tc.recordID)
+			}
+		})
+	}
+}
+
+func TestParseTicketInvalid(t *testing.T) {
+	if _, _, _, err := parseTicket("not-valid-base64!!"); err == nil {
+		t.Error("expected error for malformed base64, got nil")
+	}
+	if _, _, _, err := parseTicket(""); err == nil {
+		t.Error("expected error for empty ticket, got nil")
+	}
+}
+
+func TestWrapUnwrapSecret(t *testing.T) {
+	config := &Config{EncryptionKey: "test-bootstrap-key"}
+
+	secret, err := generateSecret()
+	if err != nil {
+		t.Fatalf("generateSecret() error = %v", err)
+	}
+
+	secretID, err := wrapSecret(config, secret)
+	if err != nil {
+		t.Fatalf("wrapSecret() error = %v", err)
+	}
+
+	got, err := unwrapSecret(config, secretID)
+	if err != nil {
+		t.Fatalf("unwrapSecret() error = %v", err)
+	}
+	if string(got) != string(secret) {
+		t.Errorf("unwrapped secret = %x, want %x", got, secret)
+	}
+}
+
+func TestEncryptDecryptCookie(t *testing.T) {
+	secret, err := generateSecret()
+	if err != nil {
+		t.Fatalf("generateSecret() error = %v", err)
+	}
+
+	nonce, ciphertext, err := encryptCookie(secret, "session=abc123")
+	if err != nil {
+		t.Fatalf("encryptCookie() error = %v", err)
+	}
+
+	plaintext, err := decryptCookie(secret, nonce, ciphertext)
+	if err != nil {
+		t.Fatalf("decryptCookie() error = %v", err)
+	}
+	if plaintext != "session=abc123" {
+		t.Errorf("plaintext = %q, want %q", plaintext, "session=abc123")
+	}
+}
+
+func TestCookieIndexHMACDeterministic(t *testing.T) {
+	config := &Config{EncryptionKey: "test-bootstrap-key"}
+
+	a := cookieIndexHMAC(config, "same-cookie")
+	b := cookieIndexHMAC(config, "same-cookie")
+	if a != b {
+		t.Errorf("cookieIndexHMAC() not deterministic: %q != %q", a, b)
+	}
+
+	c := cookieIndexHMAC(config, "different-cookie")
+	if a == c {
+		t.Error("cookieIndexHMAC() produced the same hash for different cookies")
+	}
+}
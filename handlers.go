@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/mux"
 )
 
@@ -18,8 +21,9 @@ import (
 type SaveCookieRequest struct {
 	Operation string `json:"operation"`
 	Details   struct {
-		Category string `json:"category"`
-		Cookie   string `json:"cookie"`
+		Category   string `json:"category"`
+		Cookie     string `json:"cookie"`
+		TTLSeconds int64  `json:"ttl_seconds"`
 	} `json:"details"`
 }
 
@@ -32,6 +36,10 @@ type RemoveCookieRequest struct {
 
 type SuccessResponse struct {
 	Success bool `json:"success"`
+	// Ticket is set by SaveCookie so the caller can retrieve this cookie's
+	// plaintext later via GetCookieByTicket without first calling
+	// GetCookies?tickets=true and guessing which record it just wrote.
+	Ticket string `json:"ticket,omitempty"`
 }
 
 type ErrorResponse struct {
@@ -42,7 +50,28 @@ type StatsResponse struct {
 	Details map[string]int `json:"details"`
 }
 
+// CookieData is the encrypted-at-rest record persisted in Redis. The
+// plaintext cookie never reaches Redis: it is AES-GCM encrypted with a
+// per-record secret before storage, and the secret itself is only ever
+// wrapped under the bootstrap X-Encryption-Key as SecretID.
 type CookieData struct {
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+	SecretID   string `json:"secret_id"`
+	Category   string `json:"category"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+// TicketResponse is returned in place of a plaintext cookie when a caller
+// asks for ticket handles instead of decrypted values.
+type TicketResponse struct {
+	Ticket    string `json:"ticket"`
+	Category  string `json:"category"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// PlainCookie is the decrypted, caller-facing view of a CookieData record.
+type PlainCookie struct {
 	Cookie    string `json:"cookie"`
 	Category  string `json:"category"`
 	Timestamp int64  `json:"timestamp"`
@@ -61,44 +90,159 @@ func sendSuccessResponse(w http.ResponseWriter) {
 	json.NewEncoder(w).Encode(SuccessResponse{Success: true})
 }
 
-// Middleware for API key authentication
+// sendSuccessResponseWithTicket sends a success response carrying the ticket
+// for the record the caller just saved.
+func sendSuccessResponseWithTicket(w http.ResponseWriter, ticket string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SuccessResponse{Success: true, Ticket: ticket})
+}
+
+// contextKey namespaces values this package stores on the request context,
+// so they don't collide with keys set by other middleware.
+type contextKey string
+
+const apiKeyConfigContextKey contextKey = "apiKeyConfig"
+
+// Middleware for API key authentication. The resolved key's quota/ACL is
+// attached to the request context so downstream handlers can enforce the
+// allowed_cookie_types ACL without looking the key up again.
 func Authenticate(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		config := LoadConfig()
+		config := GetConfig()
 		apiKey := r.Header.Get("x-api-key")
-		if apiKey != config.APIKey {
+		keyConfig, ok := config.APIKeys[apiKey]
+		if !ok {
 			sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), apiKeyConfigContextKey, keyConfig)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
 
+// authorizeCookieType enforces the authenticated key's allowed_cookie_types
+// ACL, so a partner key scoped to its own cookie_type can't read or write
+// another partner's namespace. A key with no allow-list is unrestricted.
+func authorizeCookieType(r *http.Request, cookieType string) bool {
+	keyConfig, ok := r.Context().Value(apiKeyConfigContextKey).(APIKeyConfig)
+	if !ok || len(keyConfig.AllowedCookieTypes) == 0 {
+		return true
+	}
+	for _, allowed := range keyConfig.AllowedCookieTypes {
+		if allowed == cookieType {
+			return true
+		}
+	}
+	return false
+}
+
+// userShardTag hashes {user_id:cookie_type} into a short tag and wraps it in
+// Redis Cluster hash-tag braces so every key for a given user/cookie_type
+// pair (cookie buckets, stats, index) lands on the same cluster slot.
+func userShardTag(userID, cookieType string) string {
+	sum := sha256.Sum256([]byte(userID + ":" + cookieType))
+	return hex.EncodeToString(sum[:8])
+}
+
 // Helper function to generate Redis key
 func generateCookieKey(userID, cookieType, category string) string {
-	return fmt.Sprintf("cookies:%s:%s:%s", userID, cookieType, category)
+	return fmt.Sprintf("cookies:{%s}:%s", userShardTag(userID, cookieType), category)
 }
 
 // Helper function to generate user stats key
 func generateStatsKey(userID, cookieType string) string {
-	return fmt.Sprintf("stats:%s:%s", userID, cookieType)
+	return fmt.Sprintf("stats:{%s}", userShardTag(userID, cookieType))
+}
+
+// generateCategoriesKey returns the Set key listing every category a user
+// has cookies in for a cookie_type, so GetCookies/RemoveCookie never have to
+// scan the keyspace to discover them.
+func generateCategoriesKey(userID, cookieType string) string {
+	return fmt.Sprintf("categories:{%s}", userShardTag(userID, cookieType))
+}
+
+// generateCookieIndexKey returns the reverse-index Hash key mapping a
+// cookie's HMAC to "category:record_id", so RemoveCookie can locate a
+// record in O(1) instead of scanning every category bucket.
+func generateCookieIndexKey(userID, cookieType string) string {
+	return fmt.Sprintf("cookie_index:{%s}", userShardTag(userID, cookieType))
+}
+
+// generateExpiryKey returns the Sorted Set key scoring each cookie record
+// with ttl_seconds by its absolute unix expiration time.
+func generateExpiryKey(userID, cookieType string) string {
+	return fmt.Sprintf("expiry:{%s}", userShardTag(userID, cookieType))
+}
+
+// knownBucketsKey is a single global Set of "user_id|cookie_type" pairs that
+// have at least one TTL'd cookie, so the background sweeper in main doesn't
+// have to discover them by scanning the keyspace.
+const knownBucketsKey = "known_buckets"
+
+func bucketID(userID, cookieType string) string {
+	return userID + "|" + cookieType
+}
+
+// expiryMember packs the fields the sweeper/GetCookies need to clean up an
+// expired record into a single Sorted Set member: category, record id and
+// cookie-index HMAC. category is client-supplied and may itself contain
+// colons; parseExpiryMember below splits right-anchored so it can still be
+// isolated correctly, since record id and idx are fixed-format hex and
+// therefore always colon-free.
+func expiryMember(category, recordID, idx string) string {
+	return category + ":" + recordID + ":" + idx
+}
+
+// parseExpiryMember reverses expiryMember.
+func parseExpiryMember(member string) (category, recordID, idx string, ok bool) {
+	idxSep := strings.LastIndex(member, ":")
+	if idxSep < 0 {
+		return "", "", "", false
+	}
+	idx = member[idxSep+1:]
+	rest := member[:idxSep]
+
+	recordSep := strings.LastIndex(rest, ":")
+	if recordSep < 0 {
+		return "", "", "", false
+	}
+	return rest[:recordSep], rest[recordSep+1:], idx, true
+}
+
+// parseIndexEntry reverses the "category:record_id" string written to the
+// reverse index. It splits right-anchored, the same way parseExpiryMember
+// does, since category is client-supplied and may itself contain colons;
+// record ids are always hex and therefore colon-free, so the last ":" in
+// the entry unambiguously separates the two regardless of the category.
+func parseIndexEntry(entry string) (category, recordID string, ok bool) {
+	sep := strings.LastIndex(entry, ":")
+	if sep < 0 {
+		return "", "", false
+	}
+	return entry[:sep], entry[sep+1:], true
 }
 
 // Save cookie handler
 func SaveCookie(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
 	vars := mux.Vars(r)
 	userID := vars["user_id"]
 	cookieType := vars["cookie_type"]
 
+	if !authorizeCookieType(r, cookieType) {
+		errorAndRecord(w, "Forbidden", http.StatusForbidden, "saveCookie", cookieType, start)
+		return
+	}
+
 	var req SaveCookieRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		sendErrorResponse(w, "Invalid JSON", http.StatusBadRequest)
+		errorAndRecord(w, "Invalid JSON", http.StatusBadRequest, "saveCookie", cookieType, start)
 		return
 	}
 
 	// Validate request
 	if req.Operation != "saveCookie" || req.Details.Cookie == "" {
-		sendErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		errorAndRecord(w, "Invalid request", http.StatusBadRequest, "saveCookie", cookieType, start)
 		return
 	}
 
@@ -110,133 +254,224 @@ func SaveCookie(w http.ResponseWriter, r *http.Request) {
 
 	ctx := context.Background()
 	timestamp := time.Now().Unix()
+	config := GetConfig()
 
-	// Create cookie data
-	cookieData := CookieData{
-		Cookie:    req.Details.Cookie,
-		Category:  category,
-		Timestamp: timestamp,
+	// Generate a per-record secret and encrypt the cookie with it
+	cookieData, recordID, secret, err := newCookieRecord(config, req.Details.Cookie, category, timestamp)
+	if err != nil {
+		errorAndRecord(w, "Internal server error", http.StatusInternalServerError, "saveCookie", cookieType, start)
+		return
 	}
 
 	cookieJSON, err := json.Marshal(cookieData)
 	if err != nil {
-		sendErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		errorAndRecord(w, "Internal server error", http.StatusInternalServerError, "saveCookie", cookieType, start)
 		return
 	}
 
-	// Save cookie to Redis Hash
+	// Save cookie to Redis Hash (keyed by the generated record id since the
+	// plaintext cookie is no longer available to use as the field name),
+	// register the category and reverse index entry, and bump stats -
+	// all atomically so a partial failure can't leave them inconsistent.
+	rdb := GetRedisClient()
 	cookieKey := generateCookieKey(userID, cookieType, category)
-	err = GetRedisClient().HSet(ctx, cookieKey, req.Details.Cookie, string(cookieJSON)).Err()
-	if err != nil {
-		sendErrorResponse(w, "Failed to save cookie", http.StatusInternalServerError)
+	categoriesKey := generateCategoriesKey(userID, cookieType)
+	cookieIndexKey := generateCookieIndexKey(userID, cookieType)
+	statsKey := generateStatsKey(userID, cookieType)
+	indexEntry := category + ":" + recordID
+	idx := cookieIndexHMAC(config, req.Details.Cookie)
+
+	// A record TTL, if any, comes from the request or falls back to the
+	// configured default; it's tracked in a companion sorted set scored by
+	// absolute expiration time since Redis has no per-field hash TTL
+	ttlSeconds := req.Details.TTLSeconds
+	if ttlSeconds == 0 {
+		ttlSeconds = int64(config.DefaultCookieTTL)
+	}
+	expiryKey := generateExpiryKey(userID, cookieType)
+	member := expiryMember(category, recordID, idx)
+
+	// Saving a cookie that's already indexed (e.g. a session refresh) mints
+	// a fresh record id, so the previous physical record has to be torn
+	// down here or it becomes a permanently orphaned duplicate: unreachable
+	// by RemoveCookie once the index is repointed, and never balanced by a
+	// matching stats decrement.
+	var previousCategory, previousRecordID string
+	var hasPrevious bool
+	prevEntry, err := rdb.HGet(ctx, cookieIndexKey, idx).Result()
+	if err == nil {
+		previousCategory, previousRecordID, hasPrevious = parseIndexEntry(prevEntry)
+		if !hasPrevious {
+			errorAndRecord(w, "Internal server error", http.StatusInternalServerError, "saveCookie", cookieType, start)
+			return
+		}
+	} else if err != redis.Nil {
+		errorAndRecord(w, "Failed to save cookie", http.StatusInternalServerError, "saveCookie", cookieType, start)
 		return
 	}
 
-	// Update stats
-	statsKey := generateStatsKey(userID, cookieType)
-	err = GetRedisClient().HIncrBy(ctx, statsKey, category, 1).Err()
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if hasPrevious {
+			pipe.HDel(ctx, generateCookieKey(userID, cookieType, previousCategory), previousRecordID)
+			pipe.HIncrBy(ctx, statsKey, previousCategory, -1)
+			pipe.ZRem(ctx, expiryKey, expiryMember(previousCategory, previousRecordID, idx))
+		}
+		pipe.HSet(ctx, cookieKey, recordID, string(cookieJSON))
+		pipe.SAdd(ctx, categoriesKey, category)
+		pipe.HSet(ctx, cookieIndexKey, idx, indexEntry)
+		pipe.HIncrBy(ctx, statsKey, category, 1)
+		if ttlSeconds > 0 {
+			expireAt := timestamp + ttlSeconds
+			pipe.ZAdd(ctx, expiryKey, &redis.Z{Score: float64(expireAt), Member: member})
+			pipe.SAdd(ctx, knownBucketsKey, bucketID(userID, cookieType))
+		}
+		return nil
+	})
 	if err != nil {
-		// Log error but don't fail the request
-		fmt.Printf("Failed to update stats: %v\n", err)
+		errorAndRecord(w, "Failed to save cookie", http.StatusInternalServerError, "saveCookie", cookieType, start)
+		return
 	}
 
-	// Return success response
-	sendSuccessResponse(w)
+	// If the resave moved the cookie to a different category, clean up the
+	// old category's stats/categories-set entries the same way RemoveCookie
+	// does once its count drops to zero
+	if hasPrevious && previousCategory != category {
+		if count, err := rdb.HGet(ctx, statsKey, previousCategory).Int(); err == nil && count <= 0 {
+			rdb.HDel(ctx, statsKey, previousCategory)
+			cookiesPerCategory.DeleteLabelValues(userID, cookieType, previousCategory)
+		}
+		previousCookieKey := generateCookieKey(userID, cookieType, previousCategory)
+		if remaining, err := rdb.HLen(ctx, previousCookieKey).Result(); err == nil && remaining == 0 {
+			rdb.SRem(ctx, categoriesKey, previousCategory)
+		}
+	}
+
+	// Update the per-category gauge so /metrics reflects this write promptly
+	if stats, err := rdb.HGetAll(ctx, statsKey).Result(); err == nil {
+		details := make(map[string]int, len(stats))
+		for cat, countStr := range stats {
+			if count, err := strconv.Atoi(countStr); err == nil {
+				details[cat] = count
+			}
+		}
+		updateStatsGauges(userID, cookieType, details)
+	}
+
+	// Return success response, including the ticket the caller needs to
+	// retrieve this cookie's plaintext via GetCookieByTicket
+	ticket := buildTicket(secret, category, recordID)
+	successWithTicketAndRecord(w, "saveCookie", cookieType, ticket, start)
 }
 
 // Remove cookie handler
 func RemoveCookie(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
 	vars := mux.Vars(r)
 	userID := vars["user_id"]
 	cookieType := vars["cookie_type"]
 
+	if !authorizeCookieType(r, cookieType) {
+		errorAndRecord(w, "Forbidden", http.StatusForbidden, "removeCookie", cookieType, start)
+		return
+	}
+
 	var req RemoveCookieRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		sendErrorResponse(w, "Invalid JSON", http.StatusBadRequest)
+		errorAndRecord(w, "Invalid JSON", http.StatusBadRequest, "removeCookie", cookieType, start)
 		return
 	}
 
 	// Validate request
 	if req.Operation != "removeCookie" || req.Details.Cookie == "" {
-		sendErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		errorAndRecord(w, "Invalid request", http.StatusBadRequest, "removeCookie", cookieType, start)
 		return
 	}
 
 	ctx := context.Background()
 	rdb := GetRedisClient()
+	config := GetConfig()
+
+	// O(1) lookup of the record's category/record id via the reverse index,
+	// instead of scanning every category bucket for the matching cookie.
+	cookieIndexKey := generateCookieIndexKey(userID, cookieType)
+	idx := cookieIndexHMAC(config, req.Details.Cookie)
+	indexEntry, err := rdb.HGet(ctx, cookieIndexKey, idx).Result()
+	if err == redis.Nil {
+		// Cookie not found - nothing to remove
+		successAndRecord(w, "removeCookie", cookieType, start)
+		return
+	} else if err != nil {
+		errorAndRecord(w, "Failed to search cookies", http.StatusInternalServerError, "removeCookie", cookieType, start)
+		return
+	}
 
-	// Find and remove cookie from all categories
-	pattern := fmt.Sprintf("cookies:%s:%s:*", userID, cookieType)
-	keys, err := rdb.Keys(ctx, pattern).Result()
+	// Split right-anchored, not left-anchored: category is client-supplied
+	// and may itself contain colons, and a left-anchored SplitN would
+	// desync on those, silently deleting the wrong record.
+	removedCategory, recordID, ok := parseIndexEntry(indexEntry)
+	if !ok {
+		errorAndRecord(w, "Internal server error", http.StatusInternalServerError, "removeCookie", cookieType, start)
+		return
+	}
+	cookieKey := generateCookieKey(userID, cookieType, removedCategory)
+	statsKey := generateStatsKey(userID, cookieType)
+	expiryKey := generateExpiryKey(userID, cookieType)
+
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, cookieKey, recordID)
+		pipe.HDel(ctx, cookieIndexKey, idx)
+		pipe.HIncrBy(ctx, statsKey, removedCategory, -1)
+		pipe.ZRem(ctx, expiryKey, expiryMember(removedCategory, recordID, idx))
+		return nil
+	})
 	if err != nil {
-		sendErrorResponse(w, "Failed to search cookies", http.StatusInternalServerError)
+		errorAndRecord(w, "Failed to remove cookie", http.StatusInternalServerError, "removeCookie", cookieType, start)
 		return
 	}
 
-	var removedCategory string
-	for _, key := range keys {
-		// Check if cookie exists in this category
-		exists, err := rdb.HExists(ctx, key, req.Details.Cookie).Result()
-		if err != nil {
-			continue
-		}
-
-		if exists {
-			// Get cookie data to extract category for stats update
-			cookieDataStr, err := rdb.HGet(ctx, key, req.Details.Cookie).Result()
-			if err == nil {
-				var cookieData CookieData
-				if json.Unmarshal([]byte(cookieDataStr), &cookieData) == nil {
-					removedCategory = cookieData.Category
-				}
-			}
-
-			// Remove the cookie
-			err = rdb.HDel(ctx, key, req.Details.Cookie).Err()
-			if err != nil {
-				sendErrorResponse(w, "Failed to remove cookie", http.StatusInternalServerError)
-				return
-			}
-			break
-		}
+	// Remove category from stats, and from the categories set if it's now
+	// empty, so GetCookies stops iterating it
+	count, err := rdb.HGet(ctx, statsKey, removedCategory).Int()
+	if err == nil && count <= 0 {
+		rdb.HDel(ctx, statsKey, removedCategory)
+		cookiesPerCategory.DeleteLabelValues(userID, cookieType, removedCategory)
+	} else if err == nil {
+		cookiesPerCategory.WithLabelValues(userID, cookieType, removedCategory).Set(float64(count))
 	}
-
-	// Update stats if cookie was found and removed
-	if removedCategory != "" {
-		statsKey := generateStatsKey(userID, cookieType)
-		err = rdb.HIncrBy(ctx, statsKey, removedCategory, -1).Err()
-		if err != nil {
-			fmt.Printf("Failed to update stats: %v\n", err)
-		}
-
-		// Remove category from stats if count reaches 0
-		count, err := rdb.HGet(ctx, statsKey, removedCategory).Int()
-		if err == nil && count <= 0 {
-			rdb.HDel(ctx, statsKey, removedCategory)
-		}
+	remaining, err := rdb.HLen(ctx, cookieKey).Result()
+	if err == nil && remaining == 0 {
+		categoriesKey := generateCategoriesKey(userID, cookieType)
+		rdb.SRem(ctx, categoriesKey, removedCategory)
 	}
 
 	// Return success response
-	sendSuccessResponse(w)
+	successAndRecord(w, "removeCookie", cookieType, start)
 }
 
 // Get cookies handler
 func GetCookies(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
 	vars := mux.Vars(r)
 	userID := vars["user_id"]
 	cookieType := vars["cookie_type"]
 
+	if !authorizeCookieType(r, cookieType) {
+		errorAndRecord(w, "Forbidden", http.StatusForbidden, "getCookies", cookieType, start)
+		return
+	}
+
 	// Parse query parameters
 	qtyStr := r.URL.Query().Get("qty")
 	randomStr := r.URL.Query().Get("random")
 	category := r.URL.Query().Get("category")
+	wantTickets := r.URL.Query().Get("tickets") == "true"
 
 	qty := 0
 	if qtyStr != "" {
 		var err error
 		qty, err = strconv.Atoi(qtyStr)
 		if err != nil || qty <= 0 {
-			sendErrorResponse(w, "Invalid qty parameter", http.StatusBadRequest)
+			errorAndRecord(w, "Invalid qty parameter", http.StatusBadRequest, "getCookies", cookieType, start)
 			return
 		}
 	}
@@ -244,70 +479,188 @@ func GetCookies(w http.ResponseWriter, r *http.Request) {
 	isRandom := randomStr == "true"
 	ctx := context.Background()
 	rdb := GetRedisClient()
+	config := GetConfig()
 
-	var cookies []CookieData
-	var pattern string
+	var records []CookieData
+	var recordIDs []string
 
-	// Build pattern based on category filter
+	// Only iterate the categories the user actually has cookies in, instead
+	// of scanning the keyspace for them
+	var categories []string
 	if category != "" {
-		pattern = fmt.Sprintf("cookies:%s:%s:%s", userID, cookieType, category)
+		categories = []string{category}
 	} else {
-		pattern = fmt.Sprintf("cookies:%s:%s:*", userID, cookieType)
+		var err error
+		categories, err = rdb.SMembers(ctx, generateCategoriesKey(userID, cookieType)).Result()
+		if err != nil {
+			errorAndRecord(w, "Failed to retrieve cookies", http.StatusInternalServerError, "getCookies", cookieType, start)
+			return
+		}
 	}
 
-	// Get all matching keys
-	keys, err := rdb.Keys(ctx, pattern).Result()
+	// Expired records are filtered out here and lazily swept, since Redis
+	// has no native per-field hash TTL to rely on
+	expiryKey := generateExpiryKey(userID, cookieType)
+	now := time.Now().Unix()
+	expiredMembers, err := rdb.ZRangeByScore(ctx, expiryKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now, 10),
+	}).Result()
 	if err != nil {
-		sendErrorResponse(w, "Failed to retrieve cookies", http.StatusInternalServerError)
+		errorAndRecord(w, "Failed to retrieve cookies", http.StatusInternalServerError, "getCookies", cookieType, start)
 		return
 	}
+	expired := make(map[string]bool, len(expiredMembers))
+	for _, member := range expiredMembers {
+		if cat, recordID, _, ok := parseExpiryMember(member); ok {
+			expired[cat+":"+recordID] = true
+		}
+	}
 
-	// Collect all cookies
-	for _, key := range keys {
-		cookieMap, err := rdb.HGetAll(ctx, key).Result()
+	// Collect all cookie records
+	for _, cat := range categories {
+		cookieMap, err := rdb.HGetAll(ctx, generateCookieKey(userID, cookieType, cat)).Result()
 		if err != nil {
 			continue
 		}
 
-		for _, cookieDataStr := range cookieMap {
+		for recordID, cookieDataStr := range cookieMap {
+			if expired[cat+":"+recordID] {
+				continue
+			}
 			var cookieData CookieData
 			if json.Unmarshal([]byte(cookieDataStr), &cookieData) == nil {
-				cookies = append(cookies, cookieData)
+				records = append(records, cookieData)
+				recordIDs = append(recordIDs, recordID)
 			}
 		}
 	}
 
+	for _, member := range expiredMembers {
+		sweepExpiredRecord(ctx, rdb, userID, cookieType, member)
+	}
+
 	// Apply random shuffle if requested
-	if isRandom && len(cookies) > 0 {
+	if isRandom && len(records) > 0 {
 		rand.Seed(time.Now().UnixNano())
-		rand.Shuffle(len(cookies), func(i, j int) {
-			cookies[i], cookies[j] = cookies[j], cookies[i]
+		rand.Shuffle(len(records), func(i, j int) {
+			records[i], records[j] = records[j], records[i]
+			recordIDs[i], recordIDs[j] = recordIDs[j], recordIDs[i]
 		})
 	}
 
 	// Apply quantity limit
-	if qty > 0 && len(cookies) > qty {
-		cookies = cookies[:qty]
+	if qty > 0 && len(records) > qty {
+		records = records[:qty]
+		recordIDs = recordIDs[:qty]
 	}
 
-	// Return cookies
 	w.Header().Set("Content-Type", "application/json")
+
+	if wantTickets {
+		tickets := make([]TicketResponse, 0, len(records))
+		for i, cookieData := range records {
+			secret, err := unwrapSecret(config, cookieData.SecretID)
+			if err != nil {
+				continue
+			}
+			tickets = append(tickets, TicketResponse{
+				Ticket:    buildTicket(secret, cookieData.Category, recordIDs[i]),
+				Category:  cookieData.Category,
+				Timestamp: cookieData.Timestamp,
+			})
+		}
+		recordOperation("getCookies", cookieType, "success", start)
+		json.NewEncoder(w).Encode(tickets)
+		return
+	}
+
+	cookies := make([]PlainCookie, 0, len(records))
+	for _, cookieData := range records {
+		secret, err := unwrapSecret(config, cookieData.SecretID)
+		if err != nil {
+			continue
+		}
+		plaintext, err := decryptCookie(secret, cookieData.Nonce, cookieData.Ciphertext)
+		if err != nil {
+			continue
+		}
+		cookies = append(cookies, PlainCookie{
+			Cookie:    plaintext,
+			Category:  cookieData.Category,
+			Timestamp: cookieData.Timestamp,
+		})
+	}
+	recordOperation("getCookies", cookieType, "success", start)
 	json.NewEncoder(w).Encode(cookies)
 }
 
+// GetCookieByTicket decrypts a single cookie record identified by an opaque
+// ticket previously issued by SaveCookie or GetCookies.
+func GetCookieByTicket(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["user_id"]
+	cookieType := vars["cookie_type"]
+	ticket := vars["ticket"]
+
+	if !authorizeCookieType(r, cookieType) {
+		sendErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	secret, category, recordID, err := parseTicket(ticket)
+	if err != nil {
+		sendErrorResponse(w, "Invalid ticket", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	cookieKey := generateCookieKey(userID, cookieType, category)
+	cookieDataStr, err := GetRedisClient().HGet(ctx, cookieKey, recordID).Result()
+	if err != nil {
+		sendErrorResponse(w, "Cookie not found", http.StatusNotFound)
+		return
+	}
+
+	var cookieData CookieData
+	if err := json.Unmarshal([]byte(cookieDataStr), &cookieData); err != nil {
+		sendErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	plaintext, err := decryptCookie(secret, cookieData.Nonce, cookieData.Ciphertext)
+	if err != nil {
+		sendErrorResponse(w, "Invalid ticket", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PlainCookie{
+		Cookie:    plaintext,
+		Category:  cookieData.Category,
+		Timestamp: cookieData.Timestamp,
+	})
+}
+
 // Get stats handler
 func GetStats(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
 	vars := mux.Vars(r)
 	userID := vars["user_id"]
 	cookieType := vars["cookie_type"]
 
+	if !authorizeCookieType(r, cookieType) {
+		errorAndRecord(w, "Forbidden", http.StatusForbidden, "getStats", cookieType, start)
+		return
+	}
+
 	ctx := context.Background()
 	statsKey := generateStatsKey(userID, cookieType)
 
 	// Get all stats
 	stats, err := GetRedisClient().HGetAll(ctx, statsKey).Result()
 	if err != nil {
-		sendErrorResponse(w, "Failed to retrieve stats", http.StatusInternalServerError)
+		errorAndRecord(w, "Failed to retrieve stats", http.StatusInternalServerError, "getStats", cookieType, start)
 		return
 	}
 
@@ -319,7 +672,12 @@ func GetStats(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Stats are the source of truth for the per-category gauge exposed on
+	// /metrics
+	updateStatsGauges(userID, cookieType, details)
+
 	// Return stats
+	recordOperation("getStats", cookieType, "success", start)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(StatsResponse{Details: details})
 }
@@ -0,0 +1,80 @@
+package main
+
+import "testing"
+
+func TestParseIndexEntryRoundTrip(t *testing.T) {
+	cases := []struct {
+		name     string
+		category string
+		recordID string
+	}{
+		{"simple category", "default", "abc123"},
+		{"category with colon", "session:mobile", "deadbeef"},
+		{"category with multiple colons", "a:b:c", "cafef00d"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			entry := tc.category + ":" + tc.recordID
+
+			gotCategory, gotRecordID, ok := parseIndexEntry(entry)
+			if !ok {
+				t.Fatalf("parseIndexEntry(%q) returned ok = false", entry)
+			}
+			if gotCategory != tc.category {
+				t.Errorf("category = %q, want %q", gotCategory, tc.category)
+			}
+			if gotRecordID != tc.recordID {
+				t.Errorf("recordID = %q, want %q", gotRecordID, tc.recordID)
+			}
+		})
+	}
+}
+
+func TestParseIndexEntryInvalid(t *testing.T) {
+	if _, _, ok := parseIndexEntry("no-colon-here"); ok {
+		t.Error("expected ok = false for an entry with no colon")
+	}
+}
+
+func TestParseExpiryMemberRoundTrip(t *testing.T) {
+	cases := []struct {
+		name     string
+		category string
+		recordID string
+		idx      string
+	}{
+		{"simple category", "default", "abc123", "idx0001"},
+		{"category with colon", "session:mobile", "deadbeef", "idx0002"},
+		{"category with multiple colons", "a:b:c", "cafef00d", "idx0003"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			member := expiryMember(tc.category, tc.recordID, tc.idx)
+
+			gotCategory, gotRecordID, gotIdx, ok := parseExpiryMember(member)
+			if !ok {
+				t.Fatalf("parseExpiryMember(%q) returned ok = false", member)
+			}
+			if gotCategory != tc.category {
+				t.Errorf("category = %q, want %q", gotCategory, tc.category)
+			}
+			if gotRecordID != tc.recordID {
+				t.Errorf("recordID = %q, want %q", gotRecordID, tc.recordID)
+			}
+			if gotIdx != tc.idx {
+				t.Errorf("idx = %q, want %q", gotIdx, tc.idx)
+			}
+		})
+	}
+}
+
+func TestParseExpiryMemberInvalid(t *testing.T) {
+	if _, _, _, ok := parseExpiryMember("no-colons-here"); ok {
+		t.Error("expected ok = false for a member with no colons")
+	}
+	if _, _, _, ok := parseExpiryMember("only:one-colon"); ok {
+		t.Error("expected ok = false for a member with only one colon")
+	}
+}
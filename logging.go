@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// logger is the package-wide structured logger, configured from LOG_LEVEL
+// once config is loaded. It defaults to info level so messages emitted
+// before InitLogger runs (e.g. while parsing config) aren't dropped.
+var logger = logrus.New()
+
+// InitLogger configures the structured logger's level from config.LogLevel.
+func InitLogger(config *Config) {
+	level, err := logrus.ParseLevel(config.LogLevel)
+	if err != nil {
+		logger.Warnf("Invalid LOG_LEVEL %q, defaulting to info", config.LogLevel)
+		level = logrus.InfoLevel
+	}
+	logger.SetLevel(level)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+}
+
+// statusRecorder wraps http.ResponseWriter to capture the status code
+// written by the handler, since http.ResponseWriter doesn't expose it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// RequestLogger logs method, path, user_id, status and duration for every
+// request, replacing the ad-hoc fmt.Printf error logging the handlers used
+// to do.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		userID := mux.Vars(r)["user_id"]
+		logger.WithFields(logrus.Fields{
+			"method":   r.Method,
+			"path":     r.URL.Path,
+			"user_id":  userID,
+			"status":   rec.status,
+			"duration": time.Since(start).String(),
+		}).Info("request completed")
+	})
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
-	"log"
+	"context"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -10,20 +14,54 @@ import (
 func main() {
 	// Load configuration
 	config := LoadConfig()
+	InitLogger(config)
 
 	// Initialize Redis
 	InitRedis(config)
 
 	// Setup routes
 	r := mux.NewRouter()
+	r.Use(RequestLogger)
 
-	// API routes with authentication
+	// API routes with rate limiting and authentication
 	api := r.PathPrefix("/api/v3").Subrouter()
-	api.HandleFunc("/cookies/{cookie_type}/{user_id}", Authenticate(HandleCookieOperations)).Methods("POST")
-	api.HandleFunc("/cookies/{cookie_type}/{user_id}", Authenticate(GetCookies)).Methods("GET")
-	api.HandleFunc("/cookies/{cookie_type}/{user_id}/stats", Authenticate(GetStats)).Methods("GET")
+	api.HandleFunc("/cookies/{cookie_type}/{user_id}", RateLimit(Authenticate(HandleCookieOperations))).Methods("POST")
+	api.HandleFunc("/cookies/{cookie_type}/{user_id}", RateLimit(Authenticate(GetCookies))).Methods("GET")
+	api.HandleFunc("/cookies/{cookie_type}/{user_id}/stats", RateLimit(Authenticate(GetStats))).Methods("GET")
+	api.HandleFunc("/cookies/{cookie_type}/{user_id}/ticket/{ticket}", RateLimit(Authenticate(GetCookieByTicket))).Methods("GET")
+	api.HandleFunc("/cookies/{cookie_type}/{user_id}/bulk", RateLimit(Authenticate(BulkImport))).Methods("POST")
+	api.HandleFunc("/cookies/{cookie_type}/{user_id}/export", RateLimit(Authenticate(BulkExport))).Methods("GET")
 
-	// Start server
-	log.Printf("Cookie API Server starting on :%s", config.Port)
-	log.Fatal(http.ListenAndServe(":"+config.Port, r))
+	// Prometheus scrape endpoint
+	r.Handle("/metrics", MetricsHandler).Methods("GET")
+
+	srv := &http.Server{
+		Addr:    ":" + config.Port,
+		Handler: r,
+	}
+
+	// The expiry sweeper runs until sweepCtx is cancelled below, so it shuts
+	// down cleanly alongside the HTTP server
+	sweepCtx, cancelSweep := context.WithCancel(context.Background())
+	go RunExpirySweeper(sweepCtx, config)
+
+	go func() {
+		logger.Infof("Cookie API Server starting on :%s", config.Port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	logger.Info("Shutting down...")
+	cancelSweep()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Errorf("Graceful shutdown failed: %v", err)
+	}
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	operationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cookie_api_operations_total",
+		Help: "Total number of cookie API operations by type, cookie_type and outcome.",
+	}, []string{"op", "cookie_type", "status"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cookie_api_request_duration_seconds",
+		Help:    "Request latency in seconds by operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"op"})
+
+	cookiesPerCategory = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cookie_api_cookies_current",
+		Help: "Current number of stored cookies by user_id, cookie_type and category.",
+	}, []string{"user_id", "cookie_type", "category"})
+)
+
+func init() {
+	prometheus.MustRegister(operationsTotal, requestDuration, cookiesPerCategory)
+}
+
+// MetricsHandler exposes the Prometheus text-format scrape endpoint.
+var MetricsHandler = promhttp.Handler()
+
+// recordOperation instruments a single handler invocation with the
+// operation counter and latency histogram.
+func recordOperation(op, cookieType, status string, start time.Time) {
+	operationsTotal.WithLabelValues(op, cookieType, status).Inc()
+	requestDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+}
+
+// errorAndRecord sends an error response and records the operation as
+// failed, so handlers don't have to instrument every early return by hand.
+func errorAndRecord(w http.ResponseWriter, message string, statusCode int, op, cookieType string, start time.Time) {
+	recordOperation(op, cookieType, "error", start)
+	sendErrorResponse(w, message, statusCode)
+}
+
+// successAndRecord sends a success response and records the operation as
+// successful.
+func successAndRecord(w http.ResponseWriter, op, cookieType string, start time.Time) {
+	recordOperation(op, cookieType, "success", start)
+	sendSuccessResponse(w)
+}
+
+// successWithTicketAndRecord sends a success response carrying a ticket and
+// records the operation as successful.
+func successWithTicketAndRecord(w http.ResponseWriter, op, cookieType, ticket string, start time.Time) {
+	recordOperation(op, cookieType, "success", start)
+	sendSuccessResponseWithTicket(w, ticket)
+}
+
+// updateStatsGauges refreshes the per-category gauge from a GetStats result
+// so /metrics always reflects the latest counts for a user/cookie_type.
+func updateStatsGauges(userID, cookieType string, details map[string]int) {
+	for category, count := range details {
+		cookiesPerCategory.WithLabelValues(userID, cookieType, category).Set(float64(count))
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// rateLimitWindow is the fixed window size used for the per-key request
+// counter. A key's effective limit for the window is RPS+Burst, so a short
+// burst above the steady-state rate is tolerated without being throttled.
+const rateLimitWindow = 1 * time.Second
+
+// RateLimit wraps Authenticate and enforces each API key's quota with a
+// Redis-backed fixed window counter on ratelimit:{apiKey}:{unix_second},
+// atomic via INCR+EXPIRE, before the request reaches authentication proper.
+func RateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := GetConfig()
+		apiKey := r.Header.Get("x-api-key")
+		keyConfig, ok := config.APIKeys[apiKey]
+		if !ok {
+			sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		allowed, remaining, err := checkRateLimit(r, apiKey, keyConfig)
+		if err != nil {
+			sendErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(rateLimitWindow.Seconds())))
+			sendErrorResponse(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// checkRateLimit increments the counter for the current window and reports
+// whether the request is within quota, using EXPIRE NX so the window's TTL
+// is only ever set by the request that created it.
+func checkRateLimit(r *http.Request, apiKey string, keyConfig APIKeyConfig) (allowed bool, remaining int, err error) {
+	rdb := GetRedisClient()
+	ctx := r.Context()
+
+	limit := keyConfig.RPS + keyConfig.Burst
+	window := time.Now().Unix()
+	key := fmt.Sprintf("ratelimit:{%s}:%d", apiKey, window)
+
+	count, err := rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	if count == 1 {
+		rdb.Expire(ctx, key, rateLimitWindow+time.Second)
+	}
+
+	if count > int64(limit) {
+		return false, 0, nil
+	}
+	return true, limit - int(count), nil
+}
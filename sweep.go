@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// sweepExpiredRecord removes a single cookie record plus its stats/index/
+// expiry bookkeeping for a member already known to be past expiration
+// (either lazily discovered by GetCookies, or by RunExpirySweeper).
+func sweepExpiredRecord(ctx context.Context, rdb redis.UniversalClient, userID, cookieType, member string) {
+	category, recordID, idx, ok := parseExpiryMember(member)
+	if !ok {
+		return
+	}
+
+	cookieKey := generateCookieKey(userID, cookieType, category)
+	cookieIndexKey := generateCookieIndexKey(userID, cookieType)
+	statsKey := generateStatsKey(userID, cookieType)
+	expiryKey := generateExpiryKey(userID, cookieType)
+
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, cookieKey, recordID)
+		pipe.HDel(ctx, cookieIndexKey, idx)
+		pipe.HIncrBy(ctx, statsKey, category, -1)
+		pipe.ZRem(ctx, expiryKey, member)
+		return nil
+	})
+	if err != nil {
+		logger.Errorf("Failed to sweep expired cookie %s/%s/%s: %v", userID, cookieType, recordID, err)
+		return
+	}
+
+	if count, err := rdb.HGet(ctx, statsKey, category).Int(); err == nil && count <= 0 {
+		rdb.HDel(ctx, statsKey, category)
+	}
+	if remaining, err := rdb.HLen(ctx, cookieKey).Result(); err == nil && remaining == 0 {
+		rdb.SRem(ctx, generateCategoriesKey(userID, cookieType), category)
+	}
+}
+
+// RunExpirySweeper periodically walks every known user/cookie_type bucket
+// and purges cookies whose ttl_seconds has elapsed, so expired sessions
+// self-purge even for accounts that aren't actively calling GetCookies.
+// It runs until ctx is cancelled, so main can stop it during graceful
+// shutdown.
+func RunExpirySweeper(ctx context.Context, config *Config) {
+	interval := time.Duration(config.SweepIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 60 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Expiry sweeper shutting down")
+			return
+		case <-ticker.C:
+			sweepAllBuckets(ctx)
+		}
+	}
+}
+
+// sweepAllBuckets sweeps every bucket registered in knownBucketsKey.
+func sweepAllBuckets(ctx context.Context) {
+	rdb := GetRedisClient()
+
+	buckets, err := rdb.SMembers(ctx, knownBucketsKey).Result()
+	if err != nil {
+		logger.Errorf("Failed to list known buckets for expiry sweep: %v", err)
+		return
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	for _, bucket := range buckets {
+		userID, cookieType, ok := parseBucketID(bucket)
+		if !ok {
+			continue
+		}
+
+		expiryKey := generateExpiryKey(userID, cookieType)
+		members, err := rdb.ZRangeByScore(ctx, expiryKey, &redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+		if err != nil {
+			logger.Errorf("Failed to scan expiry set for %s: %v", bucket, err)
+			continue
+		}
+
+		for _, member := range members {
+			sweepExpiredRecord(ctx, rdb, userID, cookieType, member)
+		}
+	}
+}
+
+// parseBucketID reverses bucketID.
+func parseBucketID(bucket string) (userID, cookieType string, ok bool) {
+	parts := strings.SplitN(bucket, "|", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}